@@ -0,0 +1,492 @@
+package install_go
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Options controls how Install fetches and verifies a Go SDK archive.
+type Options struct {
+	// Mirrors is an ordered list of URL prefixes to try, e.g.
+	// "https://dl.google.com/go". The first mirror that answers is used;
+	// on HTTP 5xx or timeout the next mirror is tried.
+	Mirrors []string
+	// Concurrency is the number of chunks downloaded in parallel. It is
+	// ignored when the server does not advertise range support.
+	Concurrency int
+	// ChunkSize is the size in bytes of each downloaded chunk.
+	ChunkSize int64
+	// MaxRetries is the number of retry attempts per chunk before the
+	// mirror is considered unreachable.
+	MaxRetries int
+	// Verifiers run in order against the downloaded archive; all must
+	// agree or the install is aborted. Defaults to []Verifier{ManifestVerifier{}}.
+	Verifiers []Verifier
+	// Progress receives download/unpack lifecycle events. Defaults to a
+	// TerminalProgress bar, preserving zenbox's original CLI UX.
+	Progress Progress
+	// Logger receives human-readable status messages. Defaults to StdLogger.
+	Logger Logger
+	// Timeout bounds the HEAD probe outright, and bounds how long each
+	// chunk/whole-file GET may go without delivering any bytes (an idle
+	// timeout, not a timeout on the transfer as a whole), so a mirror that
+	// accepts the connection but stalls fails over to the next mirror
+	// without penalizing one that is simply slow. Defaults to 30s.
+	Timeout time.Duration
+}
+
+// DefaultOptions returns the Options zenbox itself uses.
+func DefaultOptions() Options {
+	return Options{
+		Mirrors:     []string{DefaultDownloadURLPrefix},
+		Concurrency: 4,
+		ChunkSize:   8 << 20, // 8MiB
+		MaxRetries:  3,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	if len(o.Mirrors) == 0 {
+		o.Mirrors = []string{DefaultDownloadURLPrefix}
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = 8 << 20
+	}
+	if o.MaxRetries <= 0 {
+		o.MaxRetries = 3
+	}
+	if len(o.Verifiers) == 0 {
+		o.Verifiers = []Verifier{ManifestVerifier{}}
+	}
+	if o.Progress == nil {
+		o.Progress = &TerminalProgress{}
+	}
+	if o.Logger == nil {
+		o.Logger = StdLogger{}
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 30 * time.Second
+	}
+	return o
+}
+
+// archiveName returns the filename Google's Go distribution server uses for
+// the given version on the running GOOS/GOARCH, e.g. go1.20.linux-amd64.tar.gz.
+func archiveName(version string) string {
+	ext := "tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = "zip"
+	}
+	return fmt.Sprintf("%s.%s-%s.%s", version, runtime.GOOS, runtime.GOARCH, ext)
+}
+
+// Install downloads, verifies and unpacks the given Go version into dest,
+// using resumable parallel chunked downloads with mirror fallback.
+func Install(version, dest string, opts Options) error {
+	opts = opts.withDefaults()
+
+	target := archiveName(version)
+	archivePath, uri, err := downloadChunked(target, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := Verify(archivePath, uri, opts.Verifiers...); err != nil {
+		return err
+	}
+
+	unpackFn := unpackTar
+	if runtime.GOOS == "windows" {
+		unpackFn = unpackZip
+	}
+
+	os.RemoveAll(dest)
+	opts.Logger.Printf("正在解压 Go 安装包...\n")
+	if err := unpackFn(archivePath, dest, opts.Progress); err != nil {
+		return fmt.Errorf("解压 Go 到目标路径 %s 失败: %v", dest, err)
+	}
+
+	return nil
+}
+
+// chunkRange describes a single byte range of the archive and whether it has
+// already been written to disk.
+type chunkRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"` // inclusive
+	Done  bool  `json:"done"`
+}
+
+// partState is the sidecar persisted next to a partially downloaded archive
+// so that a re-run can resume instead of restarting from scratch.
+type partState struct {
+	URL    string       `json:"url"`
+	Size   int64        `json:"size"`
+	Chunks []chunkRange `json:"chunks"`
+
+	mu   sync.Mutex
+	path string
+}
+
+func loadPartState(path string) (*partState, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	st := &partState{path: path}
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("解析续传状态文件 %s 失败: %v", path, err)
+	}
+	return st, nil
+}
+
+func newPartState(path, url string, size, chunkSize int64) *partState {
+	st := &partState{URL: url, Size: size, path: path}
+	for start := int64(0); start < size; start += chunkSize {
+		end := start + chunkSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		st.Chunks = append(st.Chunks, chunkRange{Start: start, End: end})
+	}
+	return st
+}
+
+func (st *partState) markDone(i int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.Chunks[i].Done = true
+	return st.persistLocked()
+}
+
+func (st *partState) persistLocked() error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(st.path, b, os.ModePerm)
+}
+
+func (st *partState) remaining() []int {
+	var idx []int
+	for i, c := range st.Chunks {
+		if !c.Done {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// doneBytes returns how many bytes of the archive are already on disk from a
+// previous run, i.e. the sum of every chunk already marked Done.
+func (st *partState) doneBytes() int64 {
+	var n int64
+	for _, c := range st.Chunks {
+		if c.Done {
+			n += c.End - c.Start + 1
+		}
+	}
+	return n
+}
+
+// downloadChunked tries each mirror in turn and returns the path to the
+// completed archive along with the URI it was fetched from (so the caller
+// can look up the matching .sha256 file).
+func downloadChunked(target string, opts Options) (string, string, error) {
+	cachePath := filepath.Join("cache", "downloads")
+	if err := os.MkdirAll(cachePath, os.ModePerm); err != nil {
+		return "", "", err
+	}
+	destPath := filepath.Join(cachePath, target)
+	partPath := destPath + ".part.json"
+
+	var lastErr error
+	for _, prefix := range opts.Mirrors {
+		uri := fmt.Sprintf("%s/%s", prefix, target)
+		opts.Logger.Printf("开始下载 Go 安装包: %s\n", uri)
+		if err := fetchChunked(uri, destPath, partPath, opts); err != nil {
+			lastErr = err
+			opts.Logger.Printf("镜像 %s 下载失败，尝试下一个镜像: %v\n", prefix, err)
+			continue
+		}
+		return destPath, uri, nil
+	}
+	return "", "", fmt.Errorf("全部镜像下载失败: %v", lastErr)
+}
+
+// fetchChunked downloads uri into destPath. If the server supports byte
+// ranges the archive is split into opts.ChunkSize pieces and downloaded
+// opts.Concurrency at a time into a sparse file via io.WriterAt; otherwise it
+// falls back to a single sequential GET.
+func fetchChunked(uri, destPath, partPath string, opts Options) error {
+	client := &http.Client{Timeout: opts.Timeout}
+
+	head, err := client.Head(uri)
+	if err != nil {
+		return fmt.Errorf("HEAD %s 失败: %v", uri, err)
+	}
+	head.Body.Close()
+	if head.StatusCode > 299 {
+		return fmt.Errorf("HEAD %s 失败: HTTP %d", uri, head.StatusCode)
+	}
+
+	if head.Header.Get("Accept-Ranges") != "bytes" || head.ContentLength <= 0 {
+		return downloadWhole(uri, destPath, opts.Progress, opts.Timeout)
+	}
+
+	size := head.ContentLength
+
+	st, err := loadPartState(partPath)
+	if err != nil {
+		return err
+	}
+	if st == nil || st.URL != uri || st.Size != size {
+		st = newPartState(partPath, uri, size, opts.ChunkSize)
+		if err := st.persistLocked(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+
+	opts.Progress.OnStart(size, filepath.Base(destPath))
+
+	// A resumed download only calls OnWrite for the chunks fetched this run;
+	// report the bytes a previous run already completed up front so Progress
+	// implementations (the percentage bar included) start from where the
+	// transfer actually is instead of from zero.
+	if done := st.doneBytes(); done > 0 {
+		opts.Progress.OnWrite(done)
+	}
+
+	remaining := st.remaining()
+	sem := make(chan struct{}, opts.Concurrency)
+	errCh := make(chan error, len(remaining))
+	var wg sync.WaitGroup
+
+	for _, i := range remaining {
+		i := i
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := downloadChunkWithRetry(uri, f, st.Chunks[i], opts.MaxRetries, opts.Progress, opts.Timeout); err != nil {
+				errCh <- err
+				return
+			}
+			errCh <- st.markDone(i)
+		}()
+	}
+	wg.Wait()
+	close(errCh)
+
+	var finishErr error
+	for err := range errCh {
+		if err != nil && finishErr == nil {
+			finishErr = err
+		}
+	}
+	opts.Progress.OnFinish(finishErr)
+	if finishErr != nil {
+		return finishErr
+	}
+
+	os.Remove(partPath)
+	return nil
+}
+
+func downloadChunkWithRetry(uri string, f *os.File, c chunkRange, maxRetries int, prog Progress, timeout time.Duration) error {
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff(attempt))
+		}
+		if err := downloadChunkOnce(uri, f, c, prog, timeout); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("分片 [%d-%d] 下载失败，已重试 %d 次: %v", c.Start, c.End, maxRetries, lastErr)
+}
+
+func downloadChunkOnce(uri string, f *os.File, c chunkRange, prog Progress, timeout time.Duration) error {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", c.Start, c.End))
+
+	resp, body, cancel, err := doRequestWithIdleTimeout(req, timeout)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	buf := make([]byte, 32*1024)
+	offset := c.Start
+	for {
+		n, rerr := body.Read(buf)
+		if n > 0 {
+			if _, werr := f.WriteAt(buf[:n], offset); werr != nil {
+				return werr
+			}
+			offset += int64(n)
+			prog.OnWrite(int64(n))
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+	return nil
+}
+
+func downloadWhole(uri, destPath string, prog Progress, timeout time.Duration) error {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, body, cancel, err := doRequestWithIdleTimeout(req, timeout)
+	if err != nil {
+		return fmt.Errorf("下载 Go 安装包失败: %v", err)
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 {
+		return fmt.Errorf("下载 Go 安装包失败: HTTP %d: %s", resp.StatusCode, uri)
+	}
+
+	f, err := os.OpenFile(destPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	prog.OnStart(resp.ContentLength, filepath.Base(destPath))
+	_, err = io.Copy(io.MultiWriter(f, progressWriter{prog}), body)
+	prog.OnFinish(err)
+	return err
+}
+
+// idleTimeoutReader aborts the request it was built from if no Read on the
+// underlying body succeeds for timeout, by canceling the request's context.
+// Unlike http.Client.Timeout, this only fires on an idle connection: a
+// transfer that keeps delivering bytes, however slowly, is never aborted no
+// matter how long it takes in total.
+type idleTimeoutReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func newIdleTimeoutReader(r io.Reader, timeout time.Duration, cancel context.CancelFunc) *idleTimeoutReader {
+	return &idleTimeoutReader{r: r, timer: time.AfterFunc(timeout, cancel), timeout: timeout}
+}
+
+func (ir *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := ir.r.Read(p)
+	ir.timer.Reset(ir.timeout)
+	return n, err
+}
+
+// doRequestWithIdleTimeout sends req with a Transport whose
+// ResponseHeaderTimeout bounds the time to first byte, and returns the
+// response body wrapped so the request is canceled if it then goes idle for
+// timeout. Callers must read the returned body (not resp.Body) and call the
+// returned cancel func once done.
+func doRequestWithIdleTimeout(req *http.Request, timeout time.Duration) (*http.Response, io.Reader, context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	client := &http.Client{Transport: &http.Transport{ResponseHeaderTimeout: timeout}}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	return resp, newIdleTimeoutReader(resp.Body, timeout, cancel), cancel, nil
+}
+
+func backoff(attempt int) time.Duration {
+	d := time.Duration(attempt) * 500 * time.Millisecond
+	if d > 5*time.Second {
+		d = 5 * time.Second
+	}
+	return d
+}
+
+// verifySHA256 fetches uri+".sha256" and compares it against the sha256 of
+// the file at path.
+func verifySHA256(path, uri string) error {
+	sresp, err := http.Get(uri + ".sha256")
+	if err != nil {
+		return fmt.Errorf("获取文件 %s 失败: %v", uri, err)
+	}
+	defer sresp.Body.Close()
+
+	if sresp.StatusCode > 299 {
+		return fmt.Errorf("获取 %s 失败: %d", uri, sresp.StatusCode)
+	}
+
+	want, err := ioutil.ReadAll(sresp.Body)
+	if err != nil {
+		return err
+	}
+
+	sum, err := sha256File(path)
+	if err != nil {
+		return err
+	}
+
+	if sum != string(want) {
+		return fmt.Errorf("下载的文件 HASH 与服务器的文件 HASH 不匹配: %s != %s", sum, string(want))
+	}
+	return nil
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
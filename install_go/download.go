@@ -6,7 +6,6 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
-	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -15,12 +14,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/blang/semver"
-	"gopkg.in/cheggaaa/pb.v1"
 )
 
 var (
@@ -29,99 +26,104 @@ var (
 	DefaultSourceURL         = "https://go.googlesource.com/go/+refs?format=TEXT"
 )
 
-// https://dl.google.com/go
-func downloadGoVersion(target, dest string) error {
-	if DefaultProxyURL != "" {
-		os.Setenv("HTTPS_PROXY", DefaultProxyURL)
+// cleanEntryName strips the leading "go/" every official archive wraps its
+// contents in.
+func cleanEntryName(name string) string {
+	if strings.HasPrefix(name, "go/") {
+		name = name[len("go/"):]
 	}
+	return name
+}
 
-	uri := fmt.Sprintf("%s/%s", DefaultDownloadURLPrefix, target)
-
-	fmt.Printf("开始下载 Go 安装包: %s\n", uri)
+// safeJoin resolves name against dest the way unpack must: it rejects any
+// entry (absolute path, "../" traversal, or a cleverly crafted mix of both)
+// whose resolved, absolute path would land outside dest, and on Windows
+// rejects reserved device filenames.
+func safeJoin(dest, name string) (string, error) {
+	name = cleanEntryName(name)
 
-	req, err := http.NewRequest("GET", uri, nil)
-	if err != nil {
-		return err
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("归档条目使用了绝对路径: %s", name)
 	}
-	req.Header.Add("User-Agent", fmt.Sprintf("golang.org-getgo/%s", target))
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("下载 Go 安装包失败: %v", err)
-	}
-	defer resp.Body.Close()
+	// filepath.Join followed by Clean collapses any ".." segments, but a
+	// name like "../../etc/passwd" must still be caught rather than silently
+	// climbing out of dest, so the result is checked against dest below.
+	path := filepath.Join(dest, filepath.FromSlash(name))
 
-	if resp.StatusCode > 299 {
-		return fmt.Errorf("下载 Go 安装包失败: HTTP %d: %s", resp.StatusCode, uri)
-	}
-
-	size, err := strconv.Atoi(resp.Header.Get("Content-Length"))
+	absDest, err := filepath.Abs(dest)
 	if err != nil {
-		return err
+		return "", err
 	}
-
-	cachePath := filepath.Join("cache", "downloads")
-	os.MkdirAll(cachePath, os.ModePerm)
-	targetName := filepath.Join(cachePath, target)
-	os.Remove(targetName)
-	targetFile, err := os.OpenFile(targetName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.ModePerm)
+	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if absPath != absDest && !strings.HasPrefix(absPath, absDest+string(os.PathSeparator)) {
+		return "", fmt.Errorf("归档条目路径越界: %s", name)
 	}
-	defer targetFile.Close()
-
-	bar := pb.New(size).SetUnits(pb.U_BYTES)
-	bar.Start()
 
-	h := sha256.New()
-	w := io.MultiWriter(targetFile, h, bar)
-	if _, err := io.Copy(w, resp.Body); err != nil {
-		bar.Finish()
-		return err
+	if runtime.GOOS == "windows" {
+		if err := checkWindowsReservedName(filepath.Base(path)); err != nil {
+			return "", err
+		}
 	}
 
-	bar.Finish()
+	return path, nil
+}
 
-	sresp, err := http.Get(uri + ".sha256")
-	if err != nil {
-		return fmt.Errorf("获取文件 %s 失败: %v", uri, err)
+var windowsReservedNames = func() map[string]bool {
+	m := map[string]bool{"CON": true, "PRN": true, "AUX": true, "NUL": true}
+	for i := 1; i <= 9; i++ {
+		m[fmt.Sprintf("COM%d", i)] = true
+		m[fmt.Sprintf("LPT%d", i)] = true
 	}
-	defer sresp.Body.Close()
+	return m
+}()
 
-	if sresp.StatusCode > 299 {
-		return fmt.Errorf("获取 %s 失败: %d", uri, sresp.StatusCode)
+func checkWindowsReservedName(base string) error {
+	name := strings.ToUpper(base)
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
 	}
-
-	shasum, err := ioutil.ReadAll(sresp.Body)
-	if err != nil {
-		return err
+	if windowsReservedNames[name] {
+		return fmt.Errorf("归档条目使用了 Windows 保留文件名: %s", base)
 	}
+	return nil
+}
 
-	sum := fmt.Sprintf("%x", h.Sum(nil))
-	if sum != string(shasum) {
-		return fmt.Errorf("下载的文件 HASH 与服务器的文件 HASH 不匹配: %s != %s", sum, string(shasum))
+// resolveLinkTarget resolves a symlink/hardlink target recorded in an
+// archive (which may be relative to the entry's own directory) and verifies
+// it stays under dest, rejecting links that try to escape the unpack root.
+func resolveLinkTarget(dest, entryPath, linkname string) (string, error) {
+	var target string
+	if filepath.IsAbs(linkname) {
+		target = filepath.Clean(linkname)
+	} else {
+		target = filepath.Clean(filepath.Join(filepath.Dir(entryPath), filepath.FromSlash(linkname)))
 	}
 
-	unpackFn := unpackTar
-	if runtime.GOOS == "windows" {
-		unpackFn = unpackZip
+	absDest, err := filepath.Abs(dest)
+	if err != nil {
+		return "", err
 	}
-
-	os.RemoveAll(dest)
-	fmt.Println("正在解压 Go 安装包...")
-	if err := unpackFn(targetFile.Name(), dest); err != nil {
-		return fmt.Errorf("解压 Go 到目标路径 %s 失败: %v", dest, err)
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	if absTarget != absDest && !strings.HasPrefix(absTarget, absDest+string(os.PathSeparator)) {
+		return "", fmt.Errorf("链接目标越界: %s -> %s", entryPath, linkname)
 	}
 
-	return nil
+	return target, nil
 }
 
-func unpack(dest, name string, fi os.FileInfo, r io.Reader) error {
-	if strings.HasPrefix(name, "go/") {
-		name = name[len("go/"):]
+func unpack(dest, name string, fi os.FileInfo, r io.Reader, prog Progress) error {
+	path, err := safeJoin(dest, name)
+	if err != nil {
+		return err
 	}
 
-	path := filepath.Join(dest, name)
 	if fi.IsDir() {
 		return os.MkdirAll(path, fi.Mode())
 	}
@@ -132,19 +134,44 @@ func unpack(dest, name string, fi os.FileInfo, r io.Reader) error {
 	}
 	defer f.Close()
 
-	bar := pb.New64(fi.Size()).SetUnits(pb.U_BYTES)
-	bar.Prefix(name)
-	bar.Start()
+	prog.OnStart(fi.Size(), name)
 
-	w := io.MultiWriter(f, bar)
+	w := io.MultiWriter(f, progressWriter{prog})
 
 	_, err = io.Copy(w, r)
 
-	bar.Finish()
+	prog.OnFinish(err)
 	return err
 }
 
-func unpackTar(src, dest string) error {
+func unpackSymlink(dest, name, linkname string) error {
+	path, err := safeJoin(dest, name)
+	if err != nil {
+		return err
+	}
+	if _, err := resolveLinkTarget(dest, path, linkname); err != nil {
+		return err
+	}
+
+	os.Remove(path)
+	return os.Symlink(linkname, path)
+}
+
+func unpackHardlink(dest, name, linkname string) error {
+	path, err := safeJoin(dest, name)
+	if err != nil {
+		return err
+	}
+	target, err := resolveLinkTarget(dest, path, linkname)
+	if err != nil {
+		return err
+	}
+
+	os.Remove(path)
+	return os.Link(target, path)
+}
+
+func unpackTar(src, dest string, prog Progress) error {
 	r, err := os.Open(src)
 	if err != nil {
 		return err
@@ -167,26 +194,69 @@ func unpackTar(src, dest string) error {
 			return err
 		}
 
-		if err := unpack(dest, header.Name, header.FileInfo(), tarReader); err != nil {
-			return err
+		switch header.Typeflag {
+		case tar.TypeDir:
+			path, err := safeJoin(dest, header.Name)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(path, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := unpack(dest, header.Name, header.FileInfo(), tarReader, prog); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := unpackSymlink(dest, header.Name, header.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			if err := unpackHardlink(dest, header.Name, header.Linkname); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("归档中包含不支持的文件类型 (Typeflag=%q): %s", header.Typeflag, header.Name)
 		}
 	}
 
 	return nil
 }
 
-func unpackZip(src, dest string) error {
+func unpackZip(src, dest string, prog Progress) error {
 	zr, err := zip.OpenReader(src)
 	if err != nil {
 		return err
 	}
+	defer zr.Close()
 
 	for _, f := range zr.File {
+		if f.Mode()&os.ModeSymlink != 0 {
+			fr, err := f.Open()
+			if err != nil {
+				return err
+			}
+			linkname, err := ioutil.ReadAll(fr)
+			fr.Close()
+			if err != nil {
+				return err
+			}
+			if err := unpackSymlink(dest, f.Name, string(linkname)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !f.Mode().IsRegular() && !f.FileInfo().IsDir() {
+			return fmt.Errorf("归档中包含不支持的文件类型: %s", f.Name)
+		}
+
 		fr, err := f.Open()
 		if err != nil {
 			return err
 		}
-		if err := unpack(dest, f.Name, f.FileInfo(), fr); err != nil {
+		if err := unpack(dest, f.Name, f.FileInfo(), fr, prog); err != nil {
+			fr.Close()
 			return err
 		}
 		fr.Close()
@@ -195,7 +265,23 @@ func unpackZip(src, dest string) error {
 	return nil
 }
 
+// getAllGoVersion lists every Go version zenbox knows how to install, newest
+// first. It prefers the official go.dev/dl JSON index, which only lists
+// versions that actually shipped release binaries, and falls back to
+// scraping the gitiles TEXT tag listing when that index is unreachable.
 func getAllGoVersion() ([]string, error) {
+	if releases, err := getReleases(); err == nil {
+		raw := make([]string, 0, len(releases))
+		for _, r := range releases {
+			raw = append(raw, strings.TrimPrefix(r.Version, "go"))
+		}
+		return normalizeAndSortVersions(raw), nil
+	}
+
+	return getAllGoVersionFromGitiles()
+}
+
+func getAllGoVersionFromGitiles() ([]string, error) {
 	getRemoteVersion := func(name string) ([]byte, error) {
 		if DefaultProxyURL != "" {
 			os.Setenv("HTTPS_PROXY", DefaultProxyURL)
@@ -248,41 +334,52 @@ func getAllGoVersion() ([]string, error) {
 		}
 	}
 
-	sortVersions := make([]string, 0)
-	tmp := make(map[string]string)
-
+	raw := make([]string, 0)
 	scanner := bufio.NewScanner(bytes.NewReader(b))
 	for scanner.Scan() {
 		line := scanner.Text()
 		if strings.Contains(line, "refs/tags/go") {
 			ls := strings.Fields(line)
 			if len(ls) == 2 {
-				version := strings.Replace(ls[1], "refs/tags/go", "", -1)
-				oldVersion := version
-				sections := strings.Split(version, ".")
-				switch len(sections) {
-				case 1:
-					version += ".0.0"
-				case 2:
-					if strings.Contains(version, "beta") {
-						version = strings.Replace(version, "beta", ".0-beta", -1)
-					} else if strings.Contains(version, "rc") {
-						version = strings.Replace(version, "rc", ".0-rc", -1)
-					} else {
-						version += ".0"
-					}
-				case 3:
-					if strings.Contains(version, "rc") {
-						version = strings.Replace(version, "rc", "-rc", -1)
-					} else if strings.Contains(version, "beta") {
-						version = strings.Replace(version, "beta", "-beta", -1)
-					}
-				}
-
-				tmp[version] = oldVersion
-				sortVersions = append(sortVersions, version)
+				raw = append(raw, strings.Replace(ls[1], "refs/tags/go", "", -1))
+			}
+		}
+	}
+
+	return normalizeAndSortVersions(raw), nil
+}
+
+// normalizeAndSortVersions takes raw Go version strings as found in tags and
+// release manifests (e.g. "1.20", "1.21rc2", "1.18beta1") and returns them
+// sorted newest first, converted back to their original (non-semver) form.
+func normalizeAndSortVersions(raw []string) []string {
+	sortVersions := make([]string, 0, len(raw))
+	tmp := make(map[string]string, len(raw))
+
+	for _, oldVersion := range raw {
+		version := oldVersion
+		sections := strings.Split(version, ".")
+		switch len(sections) {
+		case 1:
+			version += ".0.0"
+		case 2:
+			if strings.Contains(version, "beta") {
+				version = strings.Replace(version, "beta", ".0-beta", -1)
+			} else if strings.Contains(version, "rc") {
+				version = strings.Replace(version, "rc", ".0-rc", -1)
+			} else {
+				version += ".0"
+			}
+		case 3:
+			if strings.Contains(version, "rc") {
+				version = strings.Replace(version, "rc", "-rc", -1)
+			} else if strings.Contains(version, "beta") {
+				version = strings.Replace(version, "beta", "-beta", -1)
 			}
 		}
+
+		tmp[version] = oldVersion
+		sortVersions = append(sortVersions, version)
 	}
 
 	sort.SliceStable(sortVersions, func(i, j int) bool {
@@ -291,10 +388,10 @@ func getAllGoVersion() ([]string, error) {
 		return v1.GE(v2)
 	})
 
-	versions := make([]string, 0)
+	versions := make([]string, 0, len(sortVersions))
 	for _, value := range sortVersions {
 		versions = append(versions, tmp[value])
 	}
 
-	return versions, nil
+	return versions
 }
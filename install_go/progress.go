@@ -0,0 +1,139 @@
+package install_go
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"gopkg.in/cheggaaa/pb.v1"
+)
+
+// Progress receives lifecycle events for a single download or unpack
+// operation: one OnStart, any number of OnWrite calls as bytes move, and
+// exactly one OnFinish. OnStart and OnFinish are each called once from a
+// single goroutine, but a chunked download drives OnWrite from multiple
+// goroutines concurrently (one per in-flight chunk), so implementations
+// must make OnWrite safe for concurrent use.
+type Progress interface {
+	OnStart(total int64, phase string)
+	OnWrite(n int64)
+	OnFinish(err error)
+}
+
+// NopProgress discards all events.
+type NopProgress struct{}
+
+func (NopProgress) OnStart(total int64, phase string) {}
+func (NopProgress) OnWrite(n int64)                   {}
+func (NopProgress) OnFinish(err error)                {}
+
+// TerminalProgress renders a pb.v1 progress bar to stdout, preserving
+// zenbox's original CLI UX.
+type TerminalProgress struct {
+	bar *pb.ProgressBar
+}
+
+func (p *TerminalProgress) OnStart(total int64, phase string) {
+	bar := pb.New64(total).SetUnits(pb.U_BYTES)
+	if phase != "" {
+		bar.Prefix(phase)
+	}
+	bar.Start()
+	p.bar = bar
+}
+
+func (p *TerminalProgress) OnWrite(n int64) {
+	if p.bar != nil {
+		p.bar.Add64(n)
+	}
+}
+
+func (p *TerminalProgress) OnFinish(err error) {
+	if p.bar != nil {
+		p.bar.Finish()
+	}
+}
+
+// progressEvent is the shape of each line JSONProgress writes.
+type progressEvent struct {
+	Event string `json:"event"`
+	Phase string `json:"phase,omitempty"`
+	Total int64  `json:"total,omitempty"`
+	Bytes int64  `json:"bytes,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// JSONProgress writes one JSON object per line to W (stdout if nil), suitable
+// for piping into another process: a GUI installer, or a CI log aggregator.
+// OnWrite is safe to call concurrently, as required by chunked downloads.
+type JSONProgress struct {
+	W io.Writer
+
+	mu      sync.Mutex
+	written int64
+}
+
+func (p *JSONProgress) emit(e progressEvent) {
+	w := p.W
+	if w == nil {
+		w = os.Stdout
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(w, string(b))
+}
+
+func (p *JSONProgress) OnStart(total int64, phase string) {
+	p.mu.Lock()
+	p.written = 0
+	p.mu.Unlock()
+	p.emit(progressEvent{Event: "start", Phase: phase, Total: total})
+}
+
+func (p *JSONProgress) OnWrite(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.written += n
+	p.emit(progressEvent{Event: "write", Bytes: p.written})
+}
+
+func (p *JSONProgress) OnFinish(err error) {
+	e := progressEvent{Event: "finish"}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	p.emit(e)
+}
+
+// progressWriter adapts a Progress into an io.Writer so it can sit alongside
+// the destination file in an io.MultiWriter.
+type progressWriter struct {
+	p Progress
+}
+
+func (w progressWriter) Write(b []byte) (int, error) {
+	w.p.OnWrite(int64(len(b)))
+	return len(b), nil
+}
+
+// Logger receives human-readable status messages.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// NopLogger discards all messages.
+type NopLogger struct{}
+
+func (NopLogger) Printf(format string, args ...interface{}) {}
+
+// StdLogger writes messages to stdout, preserving zenbox's historical
+// behavior of printing status lines directly.
+type StdLogger struct{}
+
+func (StdLogger) Printf(format string, args ...interface{}) {
+	fmt.Printf(format, args...)
+}
@@ -0,0 +1,246 @@
+package install_go
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTarGz(t *testing.T, entries []tarEntry) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Mode:     0644,
+			Size:     int64(len(e.body)),
+		}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+			hdr.Size = 0
+		}
+		if e.typeflag == tar.TypeSymlink || e.typeflag == tar.TypeLink {
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader(%s): %v", e.name, err)
+		}
+		if len(e.body) > 0 {
+			if _, err := tw.Write(e.body); err != nil {
+				t.Fatalf("Write(%s): %v", e.name, err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar Close: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("gzip Close: %v", err)
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "archive.tar.gz")
+	if err := ioutil.WriteFile(src, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	return src
+}
+
+func writeZip(t *testing.T, entries []zipEntry) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		fh := &zip.FileHeader{Name: e.name}
+		fh.SetMode(e.mode)
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			t.Fatalf("CreateHeader(%s): %v", e.name, err)
+		}
+		if _, err := w.Write(e.body); err != nil {
+			t.Fatalf("Write(%s): %v", e.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip Close: %v", err)
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "archive.zip")
+	if err := ioutil.WriteFile(src, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	return src
+}
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	body     []byte
+}
+
+type zipEntry struct {
+	name string
+	mode os.FileMode
+	body []byte
+}
+
+func TestUnpackTarRejectsAttacks(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		wantErr bool
+	}{
+		{
+			name: "well-behaved regular file",
+			entries: []tarEntry{
+				{name: "go/bin", typeflag: tar.TypeDir},
+				{name: "go/bin/go", typeflag: tar.TypeReg, body: []byte("binary")},
+			},
+			wantErr: false,
+		},
+		{
+			name: "path traversal via dot dot",
+			entries: []tarEntry{
+				{name: "go/../../evil.txt", typeflag: tar.TypeReg, body: []byte("evil")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "absolute path entry",
+			entries: []tarEntry{
+				{name: "/etc/evil.txt", typeflag: tar.TypeReg, body: []byte("evil")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "symlink escaping dest",
+			entries: []tarEntry{
+				{name: "go/evil-link", typeflag: tar.TypeSymlink, linkname: "../../../etc/passwd"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "symlink staying inside dest",
+			entries: []tarEntry{
+				{name: "go/real.txt", typeflag: tar.TypeReg, body: []byte("data")},
+				{name: "go/link.txt", typeflag: tar.TypeSymlink, linkname: "real.txt"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "hardlink escaping dest",
+			entries: []tarEntry{
+				{name: "go/evil-hardlink", typeflag: tar.TypeLink, linkname: "/etc/passwd"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "device file rejected",
+			entries: []tarEntry{
+				{name: "go/dev-null", typeflag: tar.TypeChar},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fifo rejected",
+			entries: []tarEntry{
+				{name: "go/fifo", typeflag: tar.TypeFifo},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src := writeTarGz(t, tc.entries)
+			dest := t.TempDir()
+
+			err := unpackTar(src, dest, NopProgress{})
+			if tc.wantErr && err == nil {
+				t.Fatalf("unpackTar(%s): expected error, got nil", tc.name)
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("unpackTar(%s): unexpected error: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func TestUnpackZipRejectsAttacks(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []zipEntry
+		wantErr bool
+	}{
+		{
+			name: "well-behaved regular file",
+			entries: []zipEntry{
+				{name: "go/bin/", mode: os.ModeDir | 0755},
+				{name: "go/bin/go.exe", mode: 0644, body: []byte("binary")},
+			},
+			wantErr: false,
+		},
+		{
+			name: "path traversal via dot dot",
+			entries: []zipEntry{
+				{name: "go/../../evil.txt", mode: 0644, body: []byte("evil")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "symlink escaping dest",
+			entries: []zipEntry{
+				{name: "go/evil-link", mode: 0644 | os.ModeSymlink, body: []byte("../../../etc/passwd")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src := writeZip(t, tc.entries)
+			dest := t.TempDir()
+
+			err := unpackZip(src, dest, NopProgress{})
+			if tc.wantErr && err == nil {
+				t.Fatalf("unpackZip(%s): expected error, got nil", tc.name)
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("unpackZip(%s): unexpected error: %v", tc.name, err)
+			}
+		})
+	}
+}
+
+func TestCheckWindowsReservedName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"CON", true},
+		{"con.txt", true},
+		{"LPT1", true},
+		{"COM9.log", true},
+		{"console.txt", false},
+		{"go.sum", false},
+	}
+
+	for _, tc := range tests {
+		if err := checkWindowsReservedName(tc.name); (err != nil) != tc.wantErr {
+			t.Errorf("checkWindowsReservedName(%q) error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package install_go
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// Verifier checks that the archive at path, originally fetched from uri, is
+// authentic. Verify returns a non-nil error if verification fails or cannot
+// be completed; callers should treat any error as fail-closed and abort the
+// install.
+type Verifier interface {
+	Verify(path, uri string) error
+}
+
+// Verify runs every verifier in order and returns the first error. All
+// verifiers must agree; a single disagreement aborts verification.
+func Verify(path, uri string, verifiers ...Verifier) error {
+	for _, v := range verifiers {
+		if err := v.Verify(path, uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SHA256Verifier compares the archive's sha256 against the "<uri>.sha256"
+// file served by the same origin. This is the verification zenbox has always
+// done; it does not protect against a mirror that is compromised wholesale,
+// which is why it should usually be combined with PinnedHashVerifier.
+type SHA256Verifier struct{}
+
+func (SHA256Verifier) Verify(path, uri string) error {
+	return verifySHA256(path, uri)
+}
+
+// PinnedHashVerifier checks the archive's sha256 against a map of known-good
+// hashes compiled into the binary at build time, keyed by archive filename
+// (e.g. "go1.20.linux-amd64.tar.gz"). If the archive is not present in
+// Hashes, Verify has no opinion and returns nil; only an actual mismatch is
+// treated as a failure.
+type PinnedHashVerifier struct {
+	Hashes map[string]string
+}
+
+func (v PinnedHashVerifier) Verify(path, uri string) error {
+	want, ok := v.Hashes[archiveNameFromURI(uri)]
+	if !ok {
+		return nil
+	}
+
+	sum, err := sha256File(path)
+	if err != nil {
+		return err
+	}
+
+	if sum != want {
+		return fmt.Errorf("内置固定 HASH 校验失败: %s != %s", sum, want)
+	}
+	return nil
+}
+
+// DefaultSumDBURL is the GOSUMDB server TransparencyLogVerifier checks
+// against, matching the "go" command's own default.
+var DefaultSumDBURL = "https://sum.golang.org"
+
+// TransparencyLogVerifier checks the archive against the real sum.golang.org
+// transparency log. Since Go 1.21, every released toolchain is also
+// published as the pseudo-module "golang.org/toolchain@v0.0.1-<version>.
+// <goos>-<goarch>", built from the very same per-OS/arch files zenbox
+// downloads, and that module's content hash ("h1:...") is recorded in
+// GOSUMDB — it's the same check "go" itself does before switching
+// toolchains. Verify rebuilds the identical dirhash locally (treating each
+// file the release archive unpacks as a module-zip entry under
+// "golang.org/toolchain@v0.0.1-<version>.<goos>-<goarch>/...") and compares it against the signed
+// record, so it catches a corrupted download or a compromised mirror that
+// SHA256Verifier/PinnedHashVerifier alone would miss.
+//
+// This has not been exercised against the live sum.golang.org service in
+// this environment (no network access); the module version scheme and
+// lookup response format follow cmd/go's documented toolchain-switch
+// behavior.
+type TransparencyLogVerifier struct{}
+
+func (TransparencyLogVerifier) Verify(path, uri string) error {
+	modVersion, err := toolchainModuleVersion(archiveNameFromURI(uri))
+	if err != nil {
+		return err
+	}
+
+	want, err := sumdbToolchainHash(modVersion)
+	if err != nil {
+		return err
+	}
+
+	got, err := toolchainModuleHash(path, modVersion)
+	if err != nil {
+		return err
+	}
+
+	if got != want {
+		return fmt.Errorf("sum.golang.org 透明日志校验失败: %s != %s", got, want)
+	}
+	return nil
+}
+
+// toolchainModuleVersion derives the "golang.org/toolchain" pseudo-version
+// for a release archive filename such as "go1.21.0.linux-amd64.tar.gz",
+// matching the scheme cmd/go uses for toolchain auto-switch.
+func toolchainModuleVersion(name string) (string, error) {
+	base := strings.TrimSuffix(strings.TrimSuffix(name, ".tar.gz"), ".zip")
+	i := strings.LastIndex(base, ".")
+	if i < 0 {
+		return "", fmt.Errorf("无法从归档文件名解析 Go 版本: %s", name)
+	}
+	version, goosArch := base[:i], base[i+1:]
+	return fmt.Sprintf("v0.0.1-%s.%s", version, goosArch), nil
+}
+
+// sumdbToolchainHash fetches the signed dirhash record sum.golang.org has
+// for the "golang.org/toolchain" module at modVersion.
+func sumdbToolchainHash(modVersion string) (string, error) {
+	lookupURL := fmt.Sprintf("%s/lookup/golang.org/toolchain@%s", DefaultSumDBURL, modVersion)
+	resp, err := http.Get(lookupURL)
+	if err != nil {
+		return "", fmt.Errorf("查询 sum.golang.org 失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 {
+		return "", fmt.Errorf("sum.golang.org 未收录该工具链版本: HTTP %d", resp.StatusCode)
+	}
+
+	want := fmt.Sprintf("golang.org/toolchain %s ", modVersion)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, want) {
+			return strings.TrimPrefix(line, want), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("sum.golang.org 返回结果中未找到 %s 的记录", modVersion)
+}
+
+// toolchainModuleHash recomputes the dirhash ("h1:") Go's module system
+// would report for the toolchain module built from the archive at path: the
+// same regular files the release archive unpacks, renamed from their "go/"
+// prefix to "golang.org/toolchain@<modVersion>/", hashed the same way
+// dirhash.HashZip hashes any other module zip.
+func toolchainModuleHash(path, modVersion string) (string, error) {
+	prefix := fmt.Sprintf("golang.org/toolchain@%s/", modVersion)
+
+	contents := map[string][]byte{}
+	err := walkArchive(path, func(name string, r io.Reader) error {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		contents[prefix+cleanEntryName(name)] = b
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return dirhash.Hash1(names, func(name string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(contents[name])), nil
+	})
+}
+
+// walkArchive calls fn with the name and content of every regular file in
+// the tar.gz or zip archive at path, mirroring the entry types unpackTar and
+// unpackZip extract to disk.
+func walkArchive(path string, fn func(name string, r io.Reader) error) error {
+	if strings.HasSuffix(path, ".zip") {
+		return walkZipArchive(path, fn)
+	}
+	return walkTarGzArchive(path, fn)
+}
+
+func walkTarGzArchive(path string, fn func(name string, r io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if err := fn(hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func walkZipArchive(path string, fn func(name string, r io.Reader) error) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if !f.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = fn(f.Name, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ManifestVerifier compares the archive's sha256 against the go.dev/dl JSON
+// index entry for its filename, falling back to the "<uri>.sha256" file (the
+// same check SHA256Verifier does) when the index has no matching entry. This
+// is the default verifier: it avoids the extra HTTP round-trip verifySHA256
+// needs, at the cost of trusting go.dev/dl over the mirror's own .sha256.
+type ManifestVerifier struct{}
+
+func (ManifestVerifier) Verify(path, uri string) error {
+	return verifyDownload(path, uri, archiveNameFromURI(uri))
+}
+
+func archiveNameFromURI(uri string) string {
+	if i := strings.LastIndex(uri, "/"); i >= 0 {
+		return uri[i+1:]
+	}
+	return uri
+}
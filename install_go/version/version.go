@@ -0,0 +1,118 @@
+// Package version manages side-by-side Go SDK installations, modeled after
+// golang.org/x/build/version: each installed Go lives in its own versioned
+// directory under cache/sdk and is launched without touching any global
+// Go installation.
+package version
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/qingfengming/zenbox/install_go"
+)
+
+// sdkRoot is where every versioned SDK is unpacked, one directory per
+// version: cache/sdk/go1.20, cache/sdk/go1.21, and so on.
+const sdkRoot = "cache/sdk"
+
+func sdkName(version string) string {
+	if !strings.HasPrefix(version, "go") {
+		return "go" + version
+	}
+	return version
+}
+
+func sdkDir(version string) string {
+	return filepath.Join(sdkRoot, sdkName(version))
+}
+
+func sentinelPath(dir string) string {
+	return filepath.Join(dir, "unpacked.ok")
+}
+
+// Install downloads and unpacks version into its own versioned directory
+// under cache/sdk, writing an unpacked.ok sentinel only after a successful
+// extraction and sha256 verification. It is a no-op if version is already
+// installed.
+func Install(version string, opts install_go.Options) error {
+	dir := sdkDir(version)
+	if _, err := os.Stat(sentinelPath(dir)); err == nil {
+		return nil
+	}
+
+	if err := install_go.Install(sdkName(version), dir, opts); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(sentinelPath(dir), []byte{}, os.ModePerm)
+}
+
+// Run locates bin/go inside version's versioned SDK root, prepends it to
+// PATH, sets GOROOT and execs the child go tool with stdio wired through.
+func Run(version string, args ...string) error {
+	dir := sdkDir(version)
+	if _, err := os.Stat(sentinelPath(dir)); err != nil {
+		return fmt.Errorf("Go %s 尚未安装: %v", version, err)
+	}
+
+	goBin := "go"
+	if runtime.GOOS == "windows" {
+		goBin = "go.exe"
+	}
+	binPath := filepath.Join(dir, "bin", goBin)
+	if _, err := os.Stat(binPath); err != nil {
+		return fmt.Errorf("未找到 %s: %v", binPath, err)
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(binPath, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"GOROOT="+absDir,
+		"PATH="+filepath.Join(absDir, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"),
+	)
+
+	return cmd.Run()
+}
+
+// Remove deletes the versioned SDK root for version.
+func Remove(version string) error {
+	return os.RemoveAll(sdkDir(version))
+}
+
+// List returns the versions currently installed under cache/sdk, identified
+// by the presence of an unpacked.ok sentinel, e.g. ["1.20", "1.21"].
+func List() ([]string, error) {
+	entries, err := ioutil.ReadDir(sdkRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var versions []string
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "go") {
+			continue
+		}
+		if _, err := os.Stat(sentinelPath(filepath.Join(sdkRoot, e.Name()))); err != nil {
+			continue
+		}
+		versions = append(versions, strings.TrimPrefix(e.Name(), "go"))
+	}
+	sort.Strings(versions)
+	return versions, nil
+}